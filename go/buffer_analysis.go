@@ -2,17 +2,64 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
+	"math"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/bmatcuk/doublestar/v4"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
 )
 
+// pendingRequests maps an in-flight request ID to the *requestHandle
+// tracking it. Entries are added when OpAnalyzeBufferAsync /
+// OpAnalyzeWorkspaceAsync / OpAnalyzeWorkspaceStream spawn their goroutine
+// and removed once that goroutine returns, whether it ran to completion or
+// was cancelled. Request IDs get reused (e.g. a buffer re-scanned on every
+// keystroke), so although entries are keyed on the requestId string, they
+// are only removed via CompareAndDelete against the *requestHandle pointer
+// itself: a goroutine
+// finishing after its requestId has already been superseded by a newer
+// request must not delete or cancel that newer request's handle.
+var pendingRequests sync.Map
+
+// requestHandle pairs a cancel func with the request it belongs to, so
+// finishRequest can tell whether the map still points at this particular
+// invocation before removing it.
+type requestHandle struct {
+	cancel context.CancelFunc
+}
+
+// registerRequest creates a cancellable context for requestId and records
+// it in pendingRequests, returning the context and the handle to pass to
+// finishRequest.
+func registerRequest(requestId string) (context.Context, *requestHandle) {
+	ctx, cancel := context.WithCancel(context.Background())
+	handle := &requestHandle{cancel: cancel}
+	pendingRequests.Store(requestId, handle)
+	return ctx, handle
+}
+
+// finishRequest always cancels handle's own context, and removes handle
+// from pendingRequests only if it is still the entry stored under
+// requestId. If requestId has since been reused for a newer request, the
+// newer request's entry and context are left untouched.
+func finishRequest(requestId string, handle *requestHandle) {
+	pendingRequests.CompareAndDelete(requestId, handle)
+	handle.cancel()
+}
+
 type LineDiagnostic struct {
 	// number or null if workspace diagnostics
 	BufNr *int `json:"bufnr"`
@@ -76,7 +123,136 @@ func validLineRequests(lineRequests []LineDiagnosticRequest) []LineDiagnosticReq
 	return validRequests
 }
 
-func generateDiagnostics(req LineDiagnosticRequest) []LineDiagnostic {
+// defaultBase64EntropyThreshold and defaultHexEntropyThreshold are the
+// Shannon entropy (bits per character) above which a base64-ish or hex-ish
+// token respectively is considered high-confidence, absent an explicit
+// override.
+const (
+	defaultBase64EntropyThreshold = 4.5
+	defaultHexEntropyThreshold    = 3.5
+)
+
+const opIgnoreMarker = "op:ignore"
+
+// entropyConfig controls the high-entropy secret detector. threshold, when
+// set, overrides both the base64 and hex defaults with a single value.
+type entropyConfig struct {
+	threshold *float64
+}
+
+func defaultEntropyConfig() entropyConfig {
+	return entropyConfig{}
+}
+
+// highEntropyCandidatePattern matches runs of base64/hex/alphanumeric text
+// (plus the handful of symbols base64 and many token formats use) that are
+// long enough to be worth an entropy check. '=' is deliberately excluded
+// even though base64 uses it for padding: keeping it in the charset let an
+// unspaced "api_key=<value>" assignment match as a single token starting
+// at the keyword itself, leaving nothing before tokenStart for
+// secretKeywordPattern to match against and silently skipping the
+// commonest .env/shell-export secret shape.
+var highEntropyCandidatePattern = regexp.MustCompile(`[A-Za-z0-9+/_.-]{20,}`)
+
+// secretKeywordPattern is required to appear before a candidate token on the
+// same line -- it's what keeps this detector from flagging every long
+// identifier in the codebase.
+var secretKeywordPattern = regexp.MustCompile(`(?i)secret|token|key|password|api`)
+
+func shannonEntropy(s string) float64 {
+	if len(s) == 0 {
+		return 0
+	}
+
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+
+	length := float64(len(s))
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / length
+		entropy -= p * math.Log2(p)
+	}
+
+	return entropy
+}
+
+func isHexToken(token string) bool {
+	for _, r := range token {
+		isHexDigit := (r >= '0' && r <= '9') || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+		if !isHexDigit {
+			return false
+		}
+	}
+
+	return true
+}
+
+func entropyThresholdFor(token string, cfg entropyConfig) float64 {
+	if cfg.threshold != nil {
+		return *cfg.threshold
+	}
+	if isHexToken(token) {
+		return defaultHexEntropyThreshold
+	}
+
+	return defaultBase64EntropyThreshold
+}
+
+// looksLikeDictionaryWord is a cheap stand-in for a real dictionary lookup:
+// natural-language words are a single case of letters, while secrets mix
+// case, digits, and symbols.
+func looksLikeDictionaryWord(token string) bool {
+	hasMixedCase := strings.ToLower(token) != token && strings.ToUpper(token) != token
+	hasDigitOrSymbol := false
+	for _, r := range token {
+		if !((r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')) {
+			hasDigitOrSymbol = true
+			break
+		}
+	}
+
+	return !hasMixedCase && !hasDigitOrSymbol
+}
+
+func generateEntropyDiagnostics(req LineDiagnosticRequest, cfg entropyConfig) []LineDiagnostic {
+	diagnostics := []LineDiagnostic{}
+	line := req.Text
+	if len(line) == 0 || strings.Contains(line, opIgnoreMarker) {
+		return diagnostics
+	}
+
+	for _, match := range highEntropyCandidatePattern.FindAllStringIndex(line, -1) {
+		tokenStart, tokenEnd := match[0], match[1]
+		if !secretKeywordPattern.MatchString(line[:tokenStart]) {
+			continue
+		}
+
+		token := line[tokenStart:tokenEnd]
+		if looksLikeDictionaryWord(token) {
+			continue
+		}
+
+		if shannonEntropy(token) < entropyThresholdFor(token, cfg) {
+			continue
+		}
+
+		diagnostics = append(diagnostics, LineDiagnostic{
+			BufNr:      req.BufNr,
+			File:       req.File,
+			Line:       req.LineNr,
+			ColStart:   tokenStart,
+			ColEnd:     tokenEnd,
+			SecretType: "high entropy string",
+		})
+	}
+
+	return diagnostics
+}
+
+func generateDiagnostics(req LineDiagnosticRequest, entropyCfg entropyConfig) []LineDiagnostic {
 	diagnostics := []LineDiagnostic{}
 	linenr := req.LineNr
 	line := req.Text
@@ -98,20 +274,26 @@ func generateDiagnostics(req LineDiagnosticRequest) []LineDiagnostic {
 		}
 	}
 
+	diagnostics = append(diagnostics, generateEntropyDiagnostics(req, entropyCfg)...)
+
 	return diagnostics
 }
 
-func analyzeBuffer(lineRequests []LineDiagnosticRequest) []LineDiagnostic {
+func analyzeBuffer(ctx context.Context, lineRequests []LineDiagnosticRequest, entropyCfg entropyConfig) []LineDiagnostic {
 	results := []LineDiagnostic{}
 	for _, req := range lineRequests {
-		results = append(results, generateDiagnostics(req)...)
+		if ctx.Err() != nil {
+			break
+		}
+
+		results = append(results, generateDiagnostics(req, entropyCfg)...)
 	}
 
 	return results
 }
 
-func analyzeBufferJson(requestId string, lineRequests []LineDiagnosticRequest) {
-	results := analyzeBuffer(lineRequests)
+func analyzeBufferJson(ctx context.Context, requestId string, lineRequests []LineDiagnosticRequest, entropyCfg entropyConfig) {
+	results := analyzeBuffer(ctx, lineRequests, entropyCfg)
 	result, err := json.Marshal(results)
 
 	if err != nil {
@@ -136,32 +318,133 @@ func collectWorkspaceFiles(globs []string) ([]string, error) {
 	return files, nil
 }
 
-func getDiagnosticsForFile(filepath string, diagnostics *[]LineDiagnostic, wg *sync.WaitGroup) {
-	wg.Add(1)
-	diagnosticRequests := []LineDiagnosticRequest{}
-	file, openErr := os.Open(filepath)
+const (
+	defaultMaxFileSize   int64 = 1 << 20 // 1 MiB
+	defaultMaxLineLength       = 1 << 16 // 64 KiB
+
+	binarySniffSize         = 8 << 10 // 8 KiB
+	binaryNonPrintableRatio = 0.30
+)
+
+// scanLimits bounds how much of a file getDiagnosticsForFile is willing to
+// read, so a stray multi-hundred-MB bundle or binary blob can't blow up a
+// workspace scan.
+type scanLimits struct {
+	maxFileSize   int64
+	maxLineLength int
+}
+
+func defaultScanLimits() scanLimits {
+	return scanLimits{maxFileSize: defaultMaxFileSize, maxLineLength: defaultMaxLineLength}
+}
+
+// fileScanResult is a single file's contribution to a workspace scan: its
+// diagnostics, plus whether the file was skipped (too large, binary,
+// unreadable, or cut short by a read error) so callers can report a
+// skipped-file count. Overlong lines are truncated and do not mark a file
+// as skipped.
+type fileScanResult struct {
+	diagnostics []LineDiagnostic
+	skipped     bool
+}
+
+// looksBinary sniffs the first binarySniffSize bytes of file for a NUL byte
+// or a high proportion of non-printable bytes, both of which are strong
+// signals the file isn't source text worth line-scanning.
+func looksBinary(file *os.File) (bool, error) {
+	buf := make([]byte, binarySniffSize)
+	n, err := file.Read(buf)
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	buf = buf[:n]
+
+	if bytes.IndexByte(buf, 0) != -1 {
+		return true, nil
+	}
+
+	if len(buf) == 0 {
+		return false, nil
+	}
+
+	nonPrintable := 0
+	for _, b := range buf {
+		if b == '\n' || b == '\r' || b == '\t' {
+			continue
+		}
+		if b < 0x20 || b > 0x7e {
+			nonPrintable++
+		}
+	}
+
+	return float64(nonPrintable)/float64(len(buf)) > binaryNonPrintableRatio, nil
+}
+
+// getDiagnosticsForFile scans a single file and returns its diagnostics.
+// It has no shared state, so callers are free to run it from any number of
+// goroutines concurrently. Files over limits.maxFileSize and files that
+// sniff as binary are skipped outright; individual lines longer than
+// limits.maxLineLength are truncated rather than aborting the whole scan.
+func getDiagnosticsForFile(ctx context.Context, path string, entropyCfg entropyConfig, limits scanLimits) fileScanResult {
+	info, statErr := os.Stat(path)
+	if statErr != nil || info.Size() > limits.maxFileSize {
+		return fileScanResult{skipped: true}
+	}
+
+	file, openErr := os.Open(path)
 	if openErr != nil {
 		// fail gracefully
-		file.Close()
-		wg.Done()
-		return
+		return fileScanResult{skipped: true}
+	}
+	defer file.Close()
+
+	binary, sniffErr := looksBinary(file)
+	if sniffErr != nil || binary {
+		return fileScanResult{skipped: true}
 	}
-	scanner := bufio.NewScanner(file)
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fileScanResult{skipped: true}
+	}
+
+	// bufio.Scanner ties its read buffer to the max token size, so capping
+	// that size at limits.maxLineLength would make any longer line fatal
+	// (ErrTooLong) instead of truncating it. Read with a plain bufio.Reader
+	// instead, which has no such limit, and truncate each line ourselves.
+	diagnosticRequests := []LineDiagnosticRequest{}
+	reader := bufio.NewReaderSize(file, 64*1024)
 	linenr := 0
-	for scanner.Scan() {
+	var readErr error
+	for readErr == nil {
+		if ctx.Err() != nil {
+			break
+		}
+
+		var line string
+		line, readErr = reader.ReadString('\n')
+		if line == "" {
+			break
+		}
+
+		text := strings.TrimRight(line, "\r\n")
+		if len(text) > limits.maxLineLength {
+			text = text[:limits.maxLineLength]
+		}
+
 		req := LineDiagnosticRequest{
-			File:   &filepath,
+			File:   &path,
 			BufNr:  nil,
 			LineNr: linenr,
-			Text:   scanner.Text(),
+			Text:   text,
 		}
 		diagnosticRequests = append(diagnosticRequests, req)
 		linenr += 1
 	}
 
-	file.Close()
-	*diagnostics = append(*diagnostics, analyzeBuffer(diagnosticRequests)...)
-	wg.Done()
+	return fileScanResult{
+		diagnostics: analyzeBuffer(ctx, diagnosticRequests, entropyCfg),
+		skipped:     readErr != nil && readErr != io.EOF,
+	}
 }
 
 func isIgnoredPath(path string, ignorePatterns []string) bool {
@@ -175,26 +458,285 @@ func isIgnoredPath(path string, ignorePatterns []string) bool {
 	return false
 }
 
-func genDiagnosticsForWorkspace(ignorePatterns []string) []LineDiagnostic {
-	diagnostics := []LineDiagnostic{}
-	wg := sync.WaitGroup{}
+// gitignoreFrame holds the patterns contributed by a single .gitignore-style
+// file, scoped to the directory (relative to the workspace root) they were
+// read from.
+type gitignoreFrame struct {
+	dir      string
+	patterns []gitignore.Pattern
+}
+
+// gitignoreStack mirrors git's own precedence rules: patterns from files
+// closer to the path being tested win, and within a file, later lines win.
+// It is pushed/popped as filepath.WalkDir enters/leaves directories so only
+// the frames still in scope for the current path are consulted.
+type gitignoreStack struct {
+	frames []gitignoreFrame
+}
+
+// sync pops any frames whose directory is no longer an ancestor of dir,
+// i.e. the walker has moved back up (or sideways) out of that subtree.
+func (s *gitignoreStack) sync(dir string) {
+	for len(s.frames) > 0 && !isAncestorDir(s.frames[len(s.frames)-1].dir, dir) {
+		s.frames = s.frames[:len(s.frames)-1]
+	}
+}
+
+func (s *gitignoreStack) push(dir string, patterns []gitignore.Pattern) {
+	if len(patterns) > 0 {
+		s.frames = append(s.frames, gitignoreFrame{dir: dir, patterns: patterns})
+	}
+}
+
+// isIgnored applies every pattern still in scope, lowest precedence first,
+// and keeps the last non-NoMatch result -- the same "last match wins"
+// semantics git uses across a stack of .gitignore files.
+func (s *gitignoreStack) isIgnored(path string, isDir bool) bool {
+	components := strings.Split(filepath.ToSlash(path), "/")
+	result := gitignore.NoMatch
+	for _, frame := range s.frames {
+		for _, pattern := range frame.patterns {
+			if r := pattern.Match(components, isDir); r != gitignore.NoMatch {
+				result = r
+			}
+		}
+	}
+
+	return result == gitignore.Exclude
+}
+
+func isAncestorDir(dir string, path string) bool {
+	if dir == "." {
+		return true
+	}
+
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+
+	return rel == "." || !strings.HasPrefix(rel, "..")
+}
+
+func gitignoreDomain(dir string) []string {
+	if dir == "." || dir == "" {
+		return nil
+	}
+
+	return strings.Split(filepath.ToSlash(dir), "/")
+}
+
+// loadGitignorePatterns parses a single gitignore-style file (a .gitignore,
+// .git/info/exclude, or the global excludes file) into patterns scoped to
+// domain, the file's directory relative to the workspace root. A missing
+// file is not an error -- most directories don't have one.
+func loadGitignorePatterns(path string, domain []string) ([]gitignore.Pattern, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	patterns := []gitignore.Pattern{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		patterns = append(patterns, gitignore.ParsePattern(line, domain))
+	}
+
+	return patterns, nil
+}
+
+func loadGlobalGitignorePatterns() ([]gitignore.Pattern, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, nil
+	}
+
+	return loadGitignorePatterns(filepath.Join(home, ".config", "git", "ignore"), nil)
+}
+
+// scanWorkers runs workerCount goroutines pulling file paths off paths and
+// pushing each file's result onto results, so no two goroutines ever touch
+// the same diagnostics slice.
+func scanWorkers(ctx context.Context, workerCount int, paths <-chan string, results chan<- fileScanResult, entropyCfg entropyConfig, limits scanLimits) {
+	workers := sync.WaitGroup{}
+	for i := 0; i < workerCount; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for path := range paths {
+				results <- getDiagnosticsForFile(ctx, path, entropyCfg, limits)
+			}
+		}()
+	}
+
+	workers.Wait()
+	close(results)
+}
+
+// walkWorkspaceFiles scans the workspace with a bounded worker pool, calling
+// onFile with each file's result as it completes. onFile is only ever
+// called from a single collector goroutine, so callers don't need their own
+// synchronization to accumulate results.
+func walkWorkspaceFiles(ctx context.Context, ignorePatterns []string, useGitignore bool, workerCount int, entropyCfg entropyConfig, limits scanLimits, onFile func(fileScanResult)) {
+	if workerCount <= 0 {
+		workerCount = runtime.NumCPU()
+	}
+
+	paths := make(chan string)
+	results := make(chan fileScanResult)
+	go scanWorkers(ctx, workerCount, paths, results, entropyCfg, limits)
+
+	collected := make(chan struct{})
+	go func() {
+		for result := range results {
+			onFile(result)
+		}
+		close(collected)
+	}()
+
+	stack := gitignoreStack{}
+	if useGitignore {
+		if global, err := loadGlobalGitignorePatterns(); err == nil {
+			stack.push(".", global)
+		}
+		if exclude, err := loadGitignorePatterns(filepath.Join(".git", "info", "exclude"), nil); err == nil {
+			stack.push(".", exclude)
+		}
+	}
 
 	filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
-		if d.IsDir() || isIgnoredPath(path, ignorePatterns) {
+		if ctx.Err() != nil {
+			return fs.SkipAll
+		}
+
+		if err != nil {
+			return nil
+		}
+
+		dir := path
+		if !d.IsDir() {
+			dir = filepath.Dir(path)
+		}
+
+		if useGitignore {
+			stack.sync(dir)
+			if patterns, ferr := loadGitignorePatterns(filepath.Join(dir, ".gitignore"), gitignoreDomain(dir)); ferr == nil {
+				stack.push(dir, patterns)
+			}
+		}
+
+		if d.IsDir() {
+			if useGitignore && path != "." && stack.isIgnored(path, true) {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 
-		go getDiagnosticsForFile(path, &diagnostics, &wg)
+		if isIgnoredPath(path, ignorePatterns) || (useGitignore && stack.isIgnored(path, false)) {
+			return nil
+		}
+
+		select {
+		case paths <- path:
+		case <-ctx.Done():
+			return fs.SkipAll
+		}
+
 		return nil
 	})
 
-	wg.Wait()
-	return diagnostics
+	close(paths)
+	<-collected
+}
+
+// WorkspaceScanResult is the JSON shape returned by a workspace scan.
+// SkippedFiles counts files that getDiagnosticsForFile skipped for being
+// oversized, binary, unreadable, or cut short by a read error, so users can
+// tell what wasn't examined.
+type WorkspaceScanResult struct {
+	Diagnostics  []LineDiagnostic `json:"diagnostics"`
+	SkippedFiles int              `json:"skipped_files"`
+}
+
+func genDiagnosticsForWorkspace(ctx context.Context, ignorePatterns []string, useGitignore bool, workerCount int, entropyCfg entropyConfig, limits scanLimits) WorkspaceScanResult {
+	result := WorkspaceScanResult{Diagnostics: []LineDiagnostic{}}
+	walkWorkspaceFiles(ctx, ignorePatterns, useGitignore, workerCount, entropyCfg, limits, func(fileResult fileScanResult) {
+		result.Diagnostics = append(result.Diagnostics, fileResult.diagnostics...)
+		if fileResult.skipped {
+			result.SkippedFiles++
+		}
+	})
+
+	return result
 }
 
-func genDiagnosticRequestsForWorkspaceJson(requestId string, ignorePatterns []string) {
-	diagnostics := genDiagnosticsForWorkspace(ignorePatterns)
-	json, err := json.Marshal(diagnostics)
+// genDiagnosticsForWorkspaceStream behaves like genDiagnosticsForWorkspace
+// but flushes onBatch every batchSize completed files instead of waiting
+// for the whole workspace, so callers can surface partial results early. It
+// returns the total number of files skipped once the scan finishes.
+func genDiagnosticsForWorkspaceStream(ctx context.Context, ignorePatterns []string, useGitignore bool, workerCount int, entropyCfg entropyConfig, limits scanLimits, batchSize int, onBatch func([]LineDiagnostic)) int {
+	batch := []LineDiagnostic{}
+	filesInBatch := 0
+	skippedFiles := 0
+	flush := func() {
+		onBatch(batch)
+		batch = []LineDiagnostic{}
+		filesInBatch = 0
+	}
+
+	walkWorkspaceFiles(ctx, ignorePatterns, useGitignore, workerCount, entropyCfg, limits, func(fileResult fileScanResult) {
+		batch = append(batch, fileResult.diagnostics...)
+		filesInBatch++
+		if fileResult.skipped {
+			skippedFiles++
+		}
+		if filesInBatch >= batchSize {
+			flush()
+		}
+	})
+
+	if filesInBatch > 0 {
+		flush()
+	}
+
+	return skippedFiles
+}
+
+// skippedFilesEventType tags the Async.Progress event genDiagnosticRequestsForWorkspaceJson
+// emits with the skipped-file count, so callers who parse Progress payloads can tell it
+// apart from a batch of LineDiagnostic (which OpAnalyzeWorkspaceStream sends there instead).
+const skippedFilesEventType = "skipped_files"
+
+type skippedFilesEvent struct {
+	Type         string `json:"type"`
+	SkippedFiles int    `json:"skipped_files"`
+}
+
+// genDiagnosticRequestsForWorkspaceJson reports diagnostics through
+// Async.Success as a bare JSON array, matching the shape
+// OpAnalyzeWorkspaceAsync has always returned, so existing callers don't
+// need to change. The skipped-file count is surfaced separately as an
+// Async.Progress event ahead of that final Success, which is purely
+// additive: callers that only read the terminal Success payload are
+// unaffected, and callers that want visibility into what was skipped can
+// opt in by reading Progress events.
+func genDiagnosticRequestsForWorkspaceJson(ctx context.Context, requestId string, ignorePatterns []string, useGitignore bool, workerCount int, entropyCfg entropyConfig, limits scanLimits) {
+	result := genDiagnosticsForWorkspace(ctx, ignorePatterns, useGitignore, workerCount, entropyCfg, limits)
+
+	if skippedJson, err := json.Marshal(skippedFilesEvent{Type: skippedFilesEventType, SkippedFiles: result.SkippedFiles}); err == nil {
+		Async.Progress(requestId, string(skippedJson))
+	}
+
+	json, err := json.Marshal(result.Diagnostics)
 	if err != nil {
 		Async.Err(requestId, err)
 	} else {
@@ -203,9 +745,62 @@ func genDiagnosticRequestsForWorkspaceJson(requestId string, ignorePatterns []st
 	}
 }
 
+// genDiagnosticRequestsForWorkspaceStreamJson mirrors
+// genDiagnosticRequestsForWorkspaceJson, but reports each batch through
+// Async.Progress as it becomes available and marks completion with a final
+// Async.Success carrying the skipped-file count rather than delivering one
+// big JSON blob at the end.
+func genDiagnosticRequestsForWorkspaceStreamJson(ctx context.Context, requestId string, ignorePatterns []string, useGitignore bool, workerCount int, entropyCfg entropyConfig, limits scanLimits, batchSize int) {
+	var batchErr error
+	skippedFiles := genDiagnosticsForWorkspaceStream(ctx, ignorePatterns, useGitignore, workerCount, entropyCfg, limits, batchSize, func(batch []LineDiagnostic) {
+		if batchErr != nil {
+			return
+		}
+
+		chunk, err := json.Marshal(batch)
+		if err != nil {
+			batchErr = err
+			return
+		}
+
+		Async.Progress(requestId, string(chunk))
+	})
+
+	if batchErr != nil {
+		Async.Err(requestId, batchErr)
+		return
+	}
+
+	summary, err := json.Marshal(WorkspaceScanResult{SkippedFiles: skippedFiles})
+	if err != nil {
+		Async.Err(requestId, err)
+		return
+	}
+
+	Async.Success(requestId, string(summary))
+}
+
+// parseEntropyThresholdArg reads an optional entropy threshold from
+// args[index], falling back to defaultEntropyConfig() when the argument is
+// absent or empty.
+func parseEntropyThresholdArg(args []string, index int) (entropyConfig, error) {
+	cfg := defaultEntropyConfig()
+	if index >= len(args) || args[index] == "" {
+		return cfg, nil
+	}
+
+	threshold, err := strconv.ParseFloat(args[index], 64)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid entropy threshold %q: %w", args[index], err)
+	}
+
+	cfg.threshold = &threshold
+	return cfg, nil
+}
+
 func OpAnalyzeBufferAsync(args []string) error {
-	if len(args) != 2 {
-		return errors.New("Need exactly 2 arguments (request ID, then buffer line requests)")
+	if len(args) < 2 || len(args) > 3 {
+		return errors.New("Need 2 or 3 arguments (request ID, buffer line requests, then optional entropy threshold)")
 	}
 
 	var lineRequests []LineDiagnosticRequest
@@ -214,20 +809,192 @@ func OpAnalyzeBufferAsync(args []string) error {
 		return jsonParseErr
 	}
 
-	go analyzeBufferJson(args[0], lineRequests)
+	entropyCfg, thresholdErr := parseEntropyThresholdArg(args, 2)
+	if thresholdErr != nil {
+		return thresholdErr
+	}
+
+	requestId := args[0]
+	ctx, handle := registerRequest(requestId)
+
+	go func() {
+		defer finishRequest(requestId, handle)
+		analyzeBufferJson(ctx, requestId, lineRequests, entropyCfg)
+	}()
+
+	return nil
+}
+
+// OpCancelAsync cancels the in-flight request identified by requestId, if
+// any. It is a no-op if the request has already finished or was never
+// started -- there is no error for cancelling something that's already
+// done. It only signals cancellation; the owning goroutine still removes
+// its own entry from pendingRequests via finishRequest, so a requestId
+// reused for a newer request right after this call can't have its handle
+// torn down out from under it.
+func OpCancelAsync(args []string) error {
+	if len(args) != 1 {
+		return errors.New("Need exactly 1 argument (request ID to cancel)")
+	}
+
+	if value, ok := pendingRequests.Load(args[0]); ok {
+		value.(*requestHandle).cancel()
+	}
 
 	return nil
 }
 
+// gitignoreFlag opts a workspace scan into also honoring .gitignore,
+// .git/info/exclude, and the global excludes file. It is passed alongside
+// the doublestar ignore patterns rather than as a new positional argument
+// so existing callers of OpAnalyzeWorkspaceAsync keep working unchanged.
+const gitignoreFlag = "--gitignore"
+
+// workerCountFlagPrefix caps how many files are scanned concurrently, e.g.
+// "--workers=4". It defaults to runtime.NumCPU() when not given.
+const workerCountFlagPrefix = "--workers="
+
+func parseWorkerCountFlag(pattern string) (int, bool) {
+	if !strings.HasPrefix(pattern, workerCountFlagPrefix) {
+		return 0, false
+	}
+
+	count, err := strconv.Atoi(strings.TrimPrefix(pattern, workerCountFlagPrefix))
+	if err != nil || count <= 0 {
+		return 0, false
+	}
+
+	return count, true
+}
+
+// entropyThresholdFlagPrefix overrides the high-entropy secret detector's
+// threshold for a workspace scan, e.g. "--entropy-threshold=4.0".
+const entropyThresholdFlagPrefix = "--entropy-threshold="
+
+func parseEntropyThresholdFlag(pattern string) (float64, bool) {
+	if !strings.HasPrefix(pattern, entropyThresholdFlagPrefix) {
+		return 0, false
+	}
+
+	threshold, err := strconv.ParseFloat(strings.TrimPrefix(pattern, entropyThresholdFlagPrefix), 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return threshold, true
+}
+
+// maxFileSizeFlagPrefix caps how large a file can be before it's skipped
+// outright, e.g. "--max-file-size=2097152". Defaults to defaultMaxFileSize.
+const maxFileSizeFlagPrefix = "--max-file-size="
+
+func parseMaxFileSizeFlag(pattern string) (int64, bool) {
+	if !strings.HasPrefix(pattern, maxFileSizeFlagPrefix) {
+		return 0, false
+	}
+
+	size, err := strconv.ParseInt(strings.TrimPrefix(pattern, maxFileSizeFlagPrefix), 10, 64)
+	if err != nil || size <= 0 {
+		return 0, false
+	}
+
+	return size, true
+}
+
+// maxLineLengthFlagPrefix caps how many characters of a single line are
+// kept, e.g. "--max-line-length=8192". Defaults to defaultMaxLineLength.
+const maxLineLengthFlagPrefix = "--max-line-length="
+
+func parseMaxLineLengthFlag(pattern string) (int, bool) {
+	if !strings.HasPrefix(pattern, maxLineLengthFlagPrefix) {
+		return 0, false
+	}
+
+	length, err := strconv.Atoi(strings.TrimPrefix(pattern, maxLineLengthFlagPrefix))
+	if err != nil || length <= 0 {
+		return 0, false
+	}
+
+	return length, true
+}
+
+// parseWorkspaceFlags splits the flags recognized by the OpAnalyzeWorkspace*
+// entry points (gitignoreFlag, workerCountFlagPrefix, entropyThresholdFlagPrefix,
+// maxFileSizeFlagPrefix, maxLineLengthFlagPrefix) out of rawPatterns, leaving
+// the remaining doublestar ignore patterns.
+func parseWorkspaceFlags(rawPatterns []string) (ignorePatterns []string, useGitignore bool, workerCount int, entropyCfg entropyConfig, limits scanLimits) {
+	workerCount = runtime.NumCPU()
+	entropyCfg = defaultEntropyConfig()
+	limits = defaultScanLimits()
+	ignorePatterns = make([]string, 0, len(rawPatterns))
+	for _, pattern := range rawPatterns {
+		if pattern == gitignoreFlag {
+			useGitignore = true
+			continue
+		}
+		if count, ok := parseWorkerCountFlag(pattern); ok {
+			workerCount = count
+			continue
+		}
+		if threshold, ok := parseEntropyThresholdFlag(pattern); ok {
+			entropyCfg.threshold = &threshold
+			continue
+		}
+		if size, ok := parseMaxFileSizeFlag(pattern); ok {
+			limits.maxFileSize = size
+			continue
+		}
+		if length, ok := parseMaxLineLengthFlag(pattern); ok {
+			limits.maxLineLength = length
+			continue
+		}
+		ignorePatterns = append(ignorePatterns, pattern)
+	}
+
+	return ignorePatterns, useGitignore, workerCount, entropyCfg, limits
+}
+
 func OpAnalyzeWorkspaceAsync(args []string) error {
 	if len(args) < 2 {
 		return errors.New("Need at least 2 arguments (request ID, then globbing patterns)")
 	}
 
 	requestId := args[0]
-	ignorePatterns := args[1:]
+	ignorePatterns, useGitignore, workerCount, entropyCfg, limits := parseWorkspaceFlags(args[1:])
+
+	ctx, handle := registerRequest(requestId)
+
+	go func() {
+		defer finishRequest(requestId, handle)
+		genDiagnosticRequestsForWorkspaceJson(ctx, requestId, ignorePatterns, useGitignore, workerCount, entropyCfg, limits)
+	}()
+
+	return nil
+}
+
+// OpAnalyzeWorkspaceStream behaves like OpAnalyzeWorkspaceAsync but reports
+// diagnostics in batches of batchSize files via Async.Progress instead of
+// waiting for the entire workspace to finish, so large workspaces start
+// showing results immediately.
+func OpAnalyzeWorkspaceStream(args []string) error {
+	if len(args) < 3 {
+		return errors.New("Need at least 3 arguments (request ID, batch size, then globbing patterns)")
+	}
+
+	requestId := args[0]
+	batchSize, batchSizeErr := strconv.Atoi(args[1])
+	if batchSizeErr != nil || batchSize <= 0 {
+		return errors.New("Batch size must be a positive integer")
+	}
+
+	ignorePatterns, useGitignore, workerCount, entropyCfg, limits := parseWorkspaceFlags(args[2:])
+
+	ctx, handle := registerRequest(requestId)
 
-	go genDiagnosticRequestsForWorkspaceJson(requestId, ignorePatterns)
+	go func() {
+		defer finishRequest(requestId, handle)
+		genDiagnosticRequestsForWorkspaceStreamJson(ctx, requestId, ignorePatterns, useGitignore, workerCount, entropyCfg, limits, batchSize)
+	}()
 
 	return nil
 }
@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+// TestGenerateEntropyDiagnosticsUnspacedAssignment covers the .env/shell-export
+// assignment shape "KEY=value" with no surrounding spaces. highEntropyCandidatePattern
+// used to include '=' in its own charset, which let it swallow the keyword and the
+// value into a single match starting at column 0 and left nothing before the match
+// for secretKeywordPattern to check against.
+func TestGenerateEntropyDiagnosticsUnspacedAssignment(t *testing.T) {
+	line := "api_key=aZ3kQ9mP2xT7vB1nR8wL5jH0yF4cD6sK"
+	diags := generateEntropyDiagnostics(LineDiagnosticRequest{Text: line}, defaultEntropyConfig())
+	if len(diags) != 1 {
+		t.Fatalf("expected 1 diagnostic for unspaced assignment, got %d", len(diags))
+	}
+}
+
+func TestGenerateEntropyDiagnosticsUnspacedAssignmentUppercase(t *testing.T) {
+	line := "AWS_SECRET_ACCESS_KEY=aZ3kQ9mP2xT7vB1nR8wL5jH0yF4cD6sK"
+	diags := generateEntropyDiagnostics(LineDiagnosticRequest{Text: line}, defaultEntropyConfig())
+	if len(diags) != 1 {
+		t.Fatalf("expected 1 diagnostic for unspaced uppercase assignment, got %d", len(diags))
+	}
+}
+
+func TestGenerateEntropyDiagnosticsSpacedAssignment(t *testing.T) {
+	line := "api_key = aZ3kQ9mP2xT7vB1nR8wL5jH0yF4cD6sK"
+	diags := generateEntropyDiagnostics(LineDiagnosticRequest{Text: line}, defaultEntropyConfig())
+	if len(diags) != 1 {
+		t.Fatalf("expected 1 diagnostic for spaced assignment, got %d", len(diags))
+	}
+}
+
+// TestGenerateEntropyDiagnosticsNoKeyword checks a line with no keyword before
+// the candidate token. The words "secret" and "key" in it sit inside the token
+// itself, which starts at column 0, so they do not count.
+func TestGenerateEntropyDiagnosticsNoKeyword(t *testing.T) {
+	line := "this_is_just_a_long_identifier_without_any_secret_keyword_nearby"
+	diags := generateEntropyDiagnostics(LineDiagnosticRequest{Text: line}, defaultEntropyConfig())
+	if len(diags) != 0 {
+		t.Fatalf("expected 0 diagnostics without a keyword before the token, got %d", len(diags))
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// TestGitignoreStackNestedNegation covers git's own precedence rule that
+// this package is meant to mirror: a pattern in a deeper .gitignore wins
+// over one in an ancestor .gitignore, including negating it back in with
+// "!". A root .gitignore excludes *.log everywhere, but a nested
+// .gitignore re-includes one specific file.
+func TestGitignoreStackNestedNegation(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatalf("failed to create fixture subdir: %v", err)
+	}
+
+	rootPatterns, err := loadGitignorePatterns(filepath.Join(dir, ".gitignore"), gitignoreDomain("."))
+	if err != nil {
+		t.Fatalf("unexpected error loading root patterns: %v", err)
+	}
+	if len(rootPatterns) != 0 {
+		t.Fatalf("expected no root patterns before writing the file, got %d", len(rootPatterns))
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("*.log\n"), 0o644); err != nil {
+		t.Fatalf("failed to write root .gitignore: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", ".gitignore"), []byte("!important.log\n"), 0o644); err != nil {
+		t.Fatalf("failed to write nested .gitignore: %v", err)
+	}
+
+	rootPatterns, err = loadGitignorePatterns(filepath.Join(dir, ".gitignore"), gitignoreDomain("."))
+	if err != nil {
+		t.Fatalf("unexpected error loading root patterns: %v", err)
+	}
+	subPatterns, err := loadGitignorePatterns(filepath.Join(dir, "sub", ".gitignore"), gitignoreDomain("sub"))
+	if err != nil {
+		t.Fatalf("unexpected error loading nested patterns: %v", err)
+	}
+
+	stack := gitignoreStack{}
+	stack.push(".", rootPatterns)
+	stack.push("sub", subPatterns)
+
+	if !stack.isIgnored("other.log", false) {
+		t.Errorf("expected other.log to be ignored by the root pattern")
+	}
+	if !stack.isIgnored("sub/debug.log", false) {
+		t.Errorf("expected sub/debug.log to still be ignored by the root pattern")
+	}
+	if stack.isIgnored("sub/important.log", false) {
+		t.Errorf("expected sub/important.log to be re-included by the nested negation")
+	}
+}
+
+// TestGitignoreStackSyncPopsOutOfScopeFrames covers the walker leaving a
+// subtree: once sync is called with a directory that is no longer a
+// descendant of a pushed frame, that frame's patterns must stop applying.
+func TestGitignoreStackSyncPopsOutOfScopeFrames(t *testing.T) {
+	stack := gitignoreStack{}
+	stack.push(".", []gitignore.Pattern{})
+	stack.push("sub", []gitignore.Pattern{gitignore.ParsePattern("*.log", gitignoreDomain("sub"))})
+
+	if !stack.isIgnored("sub/debug.log", false) {
+		t.Fatalf("expected sub/debug.log to be ignored while sub's frame is in scope")
+	}
+
+	stack.sync("other")
+
+	if stack.isIgnored("sub/debug.log", false) {
+		t.Errorf("expected sub's frame to no longer apply after leaving its subtree")
+	}
+}